@@ -0,0 +1,43 @@
+// Command echoserver is a minimal example that echoes back every message
+// it receives over an upgraded WebSocket connection.
+package main
+
+import (
+	"log"
+	"log/slog"
+	"net/http"
+	"time"
+
+	websocket "github.com/thara/go-websocket"
+)
+
+func main() {
+	s := &http.Server{
+		Addr:         ":8080",
+		Handler:      logging(websocket.Handler(echo)),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	log.Fatal(s.ListenAndServe())
+}
+
+func echo(conn *websocket.Conn) {
+	for {
+		op, msg, err := conn.ReadMessage()
+		if err != nil {
+			slog.Debug("read failed", slog.Any("error", err))
+			return
+		}
+		if err := conn.WriteMessage(op, msg); err != nil {
+			slog.Debug("write failed", slog.Any("error", err))
+			return
+		}
+	}
+}
+
+func logging(h http.Handler) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slog.Info("request", "method", r.Method, "url", r.URL.String())
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,123 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+)
+
+// deflateTrailer is the 4-byte marker RFC 7692 §7.2.1 says a sender must
+// append after a sync-flush and the receiver must append back before
+// inflating, since the sender is required to strip it from the wire.
+var deflateTrailer = []byte{0x00, 0x00, 0xff, 0xff}
+
+// deflateFinalBlock is an empty stored block with BFINAL set, appended
+// after deflateTrailer so the reconstructed stream ends on a valid block
+// boundary. deflateTrailer alone leaves the sync-flush open-ended, which
+// makes flate.Reader report io.ErrUnexpectedEOF even once every byte of
+// the message has been correctly inflated.
+var deflateFinalBlock = []byte{0x01, 0x00, 0x00, 0xff, 0xff}
+
+// maxWindowSize is the largest permessage-deflate sliding window (2^15
+// bytes), used to bound how much previously-inflated data we keep around
+// as a preset dictionary for context-takeover continuation.
+const maxWindowSize = 32768
+
+// compressionState tracks one direction (compress or decompress) of a
+// permessage-deflate connection (RFC 7692). Per-direction context takeover
+// is independent: server_no_context_takeover governs our compressor,
+// client_no_context_takeover governs our decompressor.
+type compressionState struct {
+	level             int
+	noContextTakeover bool
+
+	writer    *flate.Writer
+	writerBuf bytes.Buffer
+
+	reader   io.ReadCloser
+	readDict []byte
+}
+
+func newCompressionState(level int, noContextTakeover bool) *compressionState {
+	return &compressionState{level: level, noContextTakeover: noContextTakeover}
+}
+
+// deflateContext holds the independent read (inflate) and write (deflate)
+// compression states for a connection that negotiated permessage-deflate.
+type deflateContext struct {
+	read  *compressionState
+	write *compressionState
+}
+
+// compress deflates msg into a single sync-flushed block and strips the
+// trailing 0x00 0x00 0xff 0xff marker, which the receiver re-appends.
+// Unless no-context-takeover was negotiated, the same *flate.Writer is
+// reused so later messages can reference earlier ones in the sliding
+// window.
+func (s *compressionState) compress(msg []byte) ([]byte, error) {
+	if s.writer == nil {
+		w, err := flate.NewWriter(&s.writerBuf, s.level)
+		if err != nil {
+			return nil, fmt.Errorf("permessage-deflate: %w", err)
+		}
+		s.writer = w
+	} else if s.noContextTakeover {
+		s.writer.Reset(&s.writerBuf)
+	}
+
+	s.writerBuf.Reset()
+	if _, err := s.writer.Write(msg); err != nil {
+		return nil, fmt.Errorf("permessage-deflate: %w", err)
+	}
+	if err := s.writer.Flush(); err != nil {
+		return nil, fmt.Errorf("permessage-deflate: %w", err)
+	}
+
+	out := bytes.TrimSuffix(s.writerBuf.Bytes(), deflateTrailer)
+	compressed := make([]byte, len(out))
+	copy(compressed, out)
+	return compressed, nil
+}
+
+// decompress inflates a message payload that arrived with RSV1 set. Unless
+// no-context-takeover was negotiated, the previous message's trailing
+// bytes are supplied as a preset dictionary so the sender's reused
+// sliding window can be resolved.
+func (s *compressionState) decompress(payload []byte) ([]byte, error) {
+	tail := append(append([]byte(nil), deflateTrailer...), deflateFinalBlock...)
+	src := bytes.NewReader(append(payload, tail...))
+
+	if s.reader == nil {
+		s.reader = flate.NewReaderDict(src, s.readDict)
+	} else {
+		resetter := s.reader.(flate.Resetter)
+		dict := s.readDict
+		if s.noContextTakeover {
+			dict = nil
+		}
+		if err := resetter.Reset(src, dict); err != nil {
+			return nil, fmt.Errorf("permessage-deflate: %w", err)
+		}
+	}
+
+	out, err := io.ReadAll(s.reader)
+	if err != nil {
+		return nil, fmt.Errorf("permessage-deflate: %w", err)
+	}
+
+	if !s.noContextTakeover {
+		s.readDict = appendDict(s.readDict, out)
+	}
+	return out, nil
+}
+
+// appendDict grows dict with data, keeping only the trailing maxWindowSize
+// bytes since that's the largest window permessage-deflate ever references.
+func appendDict(dict, data []byte) []byte {
+	dict = append(dict, data...)
+	if len(dict) > maxWindowSize {
+		dict = dict[len(dict)-maxWindowSize:]
+	}
+	return dict
+}
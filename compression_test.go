@@ -0,0 +1,52 @@
+package websocket
+
+import (
+	"compress/flate"
+	"testing"
+)
+
+func TestCompressionStateRoundTrip(t *testing.T) {
+	write := newCompressionState(flate.DefaultCompression, false)
+	read := newCompressionState(flate.DefaultCompression, false)
+
+	messages := []string{
+		"hello, websocket",
+		"",
+		"a second message sharing the same sliding window as the first",
+	}
+
+	for _, want := range messages {
+		compressed, err := write.compress([]byte(want))
+		if err != nil {
+			t.Fatalf("compress(%q): %v", want, err)
+		}
+
+		got, err := read.decompress(compressed)
+		if err != nil {
+			t.Fatalf("decompress(%q): %v", want, err)
+		}
+		if string(got) != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestCompressionStateRoundTripNoContextTakeover(t *testing.T) {
+	write := newCompressionState(flate.DefaultCompression, true)
+	read := newCompressionState(flate.DefaultCompression, true)
+
+	for _, want := range []string{"first message", "second message"} {
+		compressed, err := write.compress([]byte(want))
+		if err != nil {
+			t.Fatalf("compress(%q): %v", want, err)
+		}
+
+		got, err := read.decompress(compressed)
+		if err != nil {
+			t.Fatalf("decompress(%q): %v", want, err)
+		}
+		if string(got) != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// ErrMessageTooLarge is returned by NextReader/ReadMessage when an incoming
+// message's declared length exceeds Config.MaxMessageSize.
+var ErrMessageTooLarge = errors.New("websocket: message exceeds MaxMessageSize")
+
+// ErrFrameMaskMismatch is returned by NextReader/ReadMessage when an
+// incoming frame's masking doesn't match what RFC 6455 §5.1 requires for
+// this Conn's role: a server must receive only masked frames, and a client
+// must receive only unmasked ones. The connection should be considered
+// failed and closed once this occurs.
+var ErrFrameMaskMismatch = errors.New("websocket: received frame with masking invalid for this connection's role")
+
+// defaultWriteBufferSize bounds how much of a NextWriter message is held in
+// memory before it is flushed out as a non-final frame.
+const defaultWriteBufferSize = 4096
+
+// Config carries per-connection behavior, analogous to x/net/websocket's
+// Config, describing the handshake (server- or client-side) that has
+// already completed by the time a Conn is constructed.
+type Config struct {
+	// Origin is the value of the Origin header on the handshake request,
+	// if any.
+	Origin string
+
+	// Protocol is the subprotocol negotiated during the handshake, if any.
+	Protocol string
+
+	// MaxMessageSize bounds the size, in bytes, of a single incoming
+	// message (after fragment reassembly). The declared length is checked
+	// before any payload is allocated or read. Zero means unlimited.
+	MaxMessageSize int64
+}
+
+// Conn is a single, already-upgraded WebSocket connection. It owns the
+// hijacked or dialed net.Conn and provides message-level, not frame-level, I/O:
+// fragmented data frames are reassembled, and control frames (ping, pong,
+// close) are dispatched to handlers rather than interleaved with data.
+type Conn struct {
+	conn   net.Conn
+	buf    *bufio.ReadWriter
+	Config *Config
+
+	// masked is true for client-side connections, whose outgoing frames
+	// must be masked per RFC 6455 §5.3.
+	masked bool
+
+	// writeMu serializes frame writes onto buf.Writer and ensures only one
+	// message is ever mid-flight at a time: WriteMessage is called both by
+	// application goroutines and, via pingHandler and closeHandler, from
+	// whichever goroutine is reading, and NextWriter holds writeMu for its
+	// whole Write...Close lifetime so its fragments can't be interleaved
+	// with another message (RFC 6455 §5.4 forbids interleaving fragments
+	// of different messages on the same connection).
+	writeMu sync.Mutex
+
+	pingHandler  func([]byte) error
+	pongHandler  func([]byte) error
+	closeHandler func([]byte) error
+
+	// deflate is non-nil when permessage-deflate (RFC 7692) was negotiated
+	// during the handshake.
+	deflate *deflateContext
+}
+
+// newConn wraps an already-hijacked or already-dialed connection. buf is
+// the bufio.ReadWriter to use for frame I/O, which may already hold
+// buffered bytes read as part of the handshake. masked is true for
+// client-side connections, whose outgoing frames must be masked.
+func newConn(conn net.Conn, buf *bufio.ReadWriter, config *Config, masked bool) *Conn {
+	c := &Conn{conn: conn, buf: buf, Config: config, masked: masked}
+	c.pongHandler = func([]byte) error { return nil }
+	c.pingHandler = func(payload []byte) error { return c.WriteMessage(PongMessage, payload) }
+	c.closeHandler = func(payload []byte) error { return c.WriteMessage(CloseMessage, payload) }
+	return c
+}
+
+// Close closes the underlying network connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// SetPingHandler sets the function called when a Ping control frame
+// arrives. The default replies with a Pong echoing the same payload.
+func (c *Conn) SetPingHandler(h func(payload []byte) error) {
+	c.pingHandler = h
+}
+
+// SetPongHandler sets the function called when a Pong control frame
+// arrives. The default does nothing.
+func (c *Conn) SetPongHandler(h func(payload []byte) error) {
+	c.pongHandler = h
+}
+
+// SetCloseHandler sets the function called when a Close control frame
+// arrives, before the connection is marked closed. The default echoes the
+// Close frame back to the peer, per RFC 6455 §5.5.1.
+func (c *Conn) SetCloseHandler(h func(payload []byte) error) {
+	c.closeHandler = h
+}
+
+func (c *Conn) maxMessageSize() int64 {
+	if c.Config == nil {
+		return 0
+	}
+	return c.Config.MaxMessageSize
+}
+
+// checkFrameMasking enforces RFC 6455 §5.1: a server must receive only
+// masked frames, and a client only unmasked ones; a frame that violates
+// this must fail the connection rather than be honored.
+func (c *Conn) checkFrameMasking(h frameHeader) error {
+	expectMasked := !c.masked
+	if (h.maskingKey != nil) == expectMasked {
+		return nil
+	}
+	if expectMasked {
+		return fmt.Errorf("%w: server received an unmasked frame", ErrFrameMaskMismatch)
+	}
+	return fmt.Errorf("%w: client received a masked frame", ErrFrameMaskMismatch)
+}
+
+// handleControlFrame reads a control frame's payload and dispatches it to
+// the relevant handler. It reports whether the frame was a Close frame, in
+// which case the connection is now considered closed.
+func (c *Conn) handleControlFrame(h frameHeader) (closed bool, err error) {
+	if h.length > 125 {
+		return false, fmt.Errorf("websocket: control frame payload too large: %d bytes", h.length)
+	}
+
+	payload := make([]byte, h.length)
+	if _, err := io.ReadFull(c.buf.Reader, payload); err != nil {
+		return false, fmt.Errorf("read failed: %w", err)
+	}
+	if h.maskingKey != nil {
+		maskBytes(h.maskingKey, payload)
+	}
+
+	switch h.opcode {
+	case PingMessage:
+		return false, c.pingHandler(payload)
+	case PongMessage:
+		return false, c.pongHandler(payload)
+	case CloseMessage:
+		return true, c.closeHandler(payload)
+	default:
+		return false, fmt.Errorf("websocket: unknown control opcode: %#x", h.opcode)
+	}
+}
+
+// NextReader returns the opcode and a Reader for the next data message,
+// reassembling fragmented frames as the returned Reader is consumed and
+// answering any control frames encountered along the way. A Close frame
+// ends the connection's read side and is reported as (CloseMessage, nil,
+// io.EOF).
+func (c *Conn) NextReader() (Opcode, io.Reader, error) {
+	for {
+		h, err := parseFrameHeader(c.buf.Reader)
+		if err != nil {
+			return 0, nil, err
+		}
+		if err := c.checkFrameMasking(h); err != nil {
+			return 0, nil, err
+		}
+
+		if h.opcode.isControl() {
+			closed, err := c.handleControlFrame(h)
+			if err != nil {
+				return 0, nil, err
+			}
+			if closed {
+				return CloseMessage, nil, io.EOF
+			}
+			continue
+		}
+
+		if h.opcode == ContinuationMessage {
+			return 0, nil, fmt.Errorf("websocket: unexpected continuation frame")
+		}
+		if max := c.maxMessageSize(); max > 0 && h.length > uint64(max) {
+			return 0, nil, ErrMessageTooLarge
+		}
+
+		r := &messageReader{
+			c:       c,
+			cur:     io.LimitReader(c.buf.Reader, int64(h.length)),
+			maskKey: h.maskingKey,
+			final:   h.final,
+			read:    h.length,
+			maxSize: c.maxMessageSize(),
+		}
+
+		if !h.rsv1 {
+			return h.opcode, r, nil
+		}
+		if c.deflate == nil {
+			return 0, nil, fmt.Errorf("websocket: received compressed frame but permessage-deflate was not negotiated")
+		}
+		raw, err := io.ReadAll(r)
+		if err != nil {
+			return 0, nil, err
+		}
+		decompressed, err := c.deflate.read.decompress(raw)
+		if err != nil {
+			return 0, nil, err
+		}
+		return h.opcode, bytes.NewReader(decompressed), nil
+	}
+}
+
+// ReadMessage reads the next complete application message, reassembling
+// fragmented data frames and transparently answering control frames. It is
+// a convenience wrapper around NextReader for callers that don't need to
+// stream large payloads.
+func (c *Conn) ReadMessage() (Opcode, []byte, error) {
+	op, r, err := c.NextReader()
+	if err != nil {
+		return op, nil, err
+	}
+	data, err := io.ReadAll(r)
+	return op, data, err
+}
+
+// messageReader streams the reassembled payload of a (possibly fragmented)
+// data message, unmasking each frame's payload as it is consumed and
+// transparently advancing past any interleaved control frames.
+type messageReader struct {
+	c       *Conn
+	cur     io.Reader // remaining bytes of the current frame
+	maskKey []byte
+	maskPos int
+	final   bool
+	read    uint64 // total payload length seen so far, including cur's frame
+	maxSize int64
+}
+
+func (m *messageReader) Read(p []byte) (int, error) {
+	for {
+		if m.cur == nil {
+			if m.final {
+				return 0, io.EOF
+			}
+			if err := m.advance(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := m.cur.Read(p)
+		if n > 0 {
+			if m.maskKey != nil {
+				for i := 0; i < n; i++ {
+					p[i] ^= m.maskKey[m.maskPos%4]
+					m.maskPos++
+				}
+			}
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		m.cur = nil
+		m.maskPos = 0
+	}
+}
+
+// advance reads the next continuation frame's header, transparently
+// handling any control frames interleaved between fragments.
+func (m *messageReader) advance() error {
+	h, err := parseFrameHeader(m.c.buf.Reader)
+	if err != nil {
+		return err
+	}
+	if err := m.c.checkFrameMasking(h); err != nil {
+		return err
+	}
+	if h.opcode.isControl() {
+		if _, err := m.c.handleControlFrame(h); err != nil {
+			return err
+		}
+		return m.advance()
+	}
+	if h.opcode != ContinuationMessage {
+		return fmt.Errorf("websocket: expected continuation frame, got %#x", h.opcode)
+	}
+
+	m.read += h.length
+	if m.maxSize > 0 && m.read > uint64(m.maxSize) {
+		return ErrMessageTooLarge
+	}
+
+	m.cur = io.LimitReader(m.c.buf.Reader, int64(h.length))
+	m.maskKey = h.maskingKey
+	m.final = h.final
+	return nil
+}
+
+// WriteMessage sends payload as one or more frames with the given opcode.
+// Frames are masked with a fresh key per frame on a client-side Conn and
+// sent unmasked on a server-side one (RFC 6455 §5.3). If permessage-deflate
+// was negotiated, a data message's payload is compressed and RSV1 is set on
+// its first frame; control frames are always sent uncompressed.
+func (c *Conn) WriteMessage(op Opcode, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	rsv1 := false
+	if c.deflate != nil && !op.isControl() {
+		compressed, err := c.deflate.write.compress(payload)
+		if err != nil {
+			return err
+		}
+		payload = compressed
+		rsv1 = true
+	}
+	if err := c.writeMessageFrames(op, payload, rsv1); err != nil {
+		return err
+	}
+	return c.buf.Writer.Flush()
+}
+
+// writeMessageFrames splits payload into chunks no larger than
+// defaultWriteBufferSize, writing FIN=0 continuation frames for all but the
+// last. rsv1 is only ever set on the first frame, per RFC 7692 §7.2.3.
+// Callers must hold writeMu.
+func (c *Conn) writeMessageFrames(op Opcode, payload []byte, rsv1 bool) error {
+	for {
+		chunk := payload
+		final := true
+		if len(chunk) > defaultWriteBufferSize {
+			chunk = chunk[:defaultWriteBufferSize]
+			final = false
+		}
+
+		if err := c.writeFrame(final, rsv1, op, chunk); err != nil {
+			return err
+		}
+
+		payload = payload[len(chunk):]
+		rsv1 = false
+		op = ContinuationMessage
+		if final {
+			return nil
+		}
+	}
+}
+
+// writeFrame writes a single frame. On a client-side Conn it generates a
+// fresh 4-byte masking key and masks a copy of payload before writing, per
+// RFC 6455 §5.3; payload itself is never mutated.
+func (c *Conn) writeFrame(final, rsv1 bool, op Opcode, payload []byte) error {
+	var maskKey []byte
+	if c.masked {
+		maskKey = make([]byte, 4)
+		if _, err := rand.Read(maskKey); err != nil {
+			return fmt.Errorf("websocket: generate masking key failed: %w", err)
+		}
+		masked := make([]byte, len(payload))
+		copy(masked, payload)
+		maskBytes(maskKey, masked)
+		payload = masked
+	}
+
+	if err := writeFrameHeader(c.buf.Writer, final, rsv1, op, len(payload), maskKey); err != nil {
+		return fmt.Errorf("write frame header failed: %w", err)
+	}
+	if _, err := c.buf.Writer.Write(payload); err != nil {
+		return fmt.Errorf("write payload failed: %w", err)
+	}
+	return nil
+}
+
+// NextWriter returns a writer for a new message with the given opcode.
+// Writes are buffered up to an internal threshold and flushed out as
+// non-final (FIN=0) frames; Close must be called exactly once to emit the
+// final (FIN=1) frame and complete the message. NextWriter holds the
+// connection's write lock from this call until Close, so no other
+// goroutine's WriteMessage or NextWriter can splice a message into the
+// middle of this one's fragments.
+func (c *Conn) NextWriter(op Opcode) (io.WriteCloser, error) {
+	c.writeMu.Lock()
+	return &frameWriter{c: c, opcode: op}, nil
+}
+
+type frameWriter struct {
+	c       *Conn
+	opcode  Opcode
+	buf     []byte
+	started bool
+	closed  bool
+}
+
+func (w *frameWriter) nextOpcode() Opcode {
+	if w.started {
+		return ContinuationMessage
+	}
+	return w.opcode
+}
+
+// Write buffers p and, once more than defaultWriteBufferSize has
+// accumulated, flushes it out as non-final frames. The caller already
+// holds w.c.writeMu, acquired by NextWriter and released by Close.
+func (w *frameWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	// A compressed message can't be chunked until the whole thing has been
+	// written through the deflate stream, so buffer it all until Close.
+	if w.c.deflate != nil {
+		return len(p), nil
+	}
+
+	for len(w.buf) > defaultWriteBufferSize {
+		if err := w.c.writeFrame(false, false, w.nextOpcode(), w.buf[:defaultWriteBufferSize]); err != nil {
+			return 0, err
+		}
+		w.started = true
+		w.buf = w.buf[defaultWriteBufferSize:]
+	}
+	return len(p), nil
+}
+
+// Close emits the final frame and releases w.c.writeMu. It is a no-op
+// after the first call.
+func (w *frameWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.c.writeMu.Unlock()
+
+	payload := w.buf
+	rsv1 := false
+	if w.c.deflate != nil {
+		compressed, err := w.c.deflate.write.compress(payload)
+		if err != nil {
+			return err
+		}
+		payload = compressed
+		rsv1 = true
+	}
+
+	if err := w.c.writeMessageFrames(w.nextOpcode(), payload, rsv1); err != nil {
+		return err
+	}
+	w.buf = nil
+	return w.c.buf.Writer.Flush()
+}
@@ -0,0 +1,215 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// writeTestFrame appends a single frame to buf, masking the payload first
+// when maskKey is given, the way a real client would.
+func writeTestFrame(t *testing.T, buf *bytes.Buffer, final bool, op Opcode, payload []byte, maskKey []byte) {
+	t.Helper()
+
+	p := payload
+	if maskKey != nil {
+		p = append([]byte(nil), payload...)
+		maskBytes(maskKey, p)
+	}
+
+	w := bufio.NewWriter(buf)
+	if err := writeFrameHeader(w, final, false, op, len(p), maskKey); err != nil {
+		t.Fatalf("writeFrameHeader: %v", err)
+	}
+	if _, err := w.Write(p); err != nil {
+		t.Fatalf("write payload: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+}
+
+// newTestConn builds a Conn that reads frames from a fixed byte stream and
+// discards anything it writes.
+func newTestConn(frames []byte, config *Config) *Conn {
+	if config == nil {
+		config = &Config{}
+	}
+	buf := bufio.NewReadWriter(bufio.NewReader(bytes.NewReader(frames)), bufio.NewWriter(io.Discard))
+	return newConn(nil, buf, config, false)
+}
+
+func TestConnReadMessageReassemblesFragments(t *testing.T) {
+	maskKey := []byte{0xaa, 0xbb, 0xcc, 0xdd}
+	var buf bytes.Buffer
+	writeTestFrame(t, &buf, false, TextMessage, []byte("hello, "), maskKey)
+	writeTestFrame(t, &buf, true, ContinuationMessage, []byte("world"), maskKey)
+
+	conn := newTestConn(buf.Bytes(), nil)
+	op, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if op != TextMessage {
+		t.Fatalf("got opcode %v, want TextMessage", op)
+	}
+	if got := string(msg); got != "hello, world" {
+		t.Fatalf("got message %q, want %q", got, "hello, world")
+	}
+}
+
+func TestConnReadMessageUnmasksPayload(t *testing.T) {
+	maskKey := []byte{0x01, 0x02, 0x03, 0x04}
+	var buf bytes.Buffer
+	writeTestFrame(t, &buf, true, BinaryMessage, []byte("client payload"), maskKey)
+
+	conn := newTestConn(buf.Bytes(), nil)
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if got := string(msg); got != "client payload" {
+		t.Fatalf("got %q, want unmasked %q", got, "client payload")
+	}
+}
+
+func TestConnReadMessageRejectsTooLarge(t *testing.T) {
+	maskKey := []byte{0xaa, 0xbb, 0xcc, 0xdd}
+	var buf bytes.Buffer
+	writeTestFrame(t, &buf, true, BinaryMessage, make([]byte, 100), maskKey)
+
+	conn := newTestConn(buf.Bytes(), &Config{MaxMessageSize: 10})
+	_, _, err := conn.ReadMessage()
+	if !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("got error %v, want ErrMessageTooLarge", err)
+	}
+}
+
+func TestConnReadMessageRejectsTooLargeAcrossFragments(t *testing.T) {
+	maskKey := []byte{0xaa, 0xbb, 0xcc, 0xdd}
+	var buf bytes.Buffer
+	writeTestFrame(t, &buf, false, TextMessage, make([]byte, 6), maskKey)
+	writeTestFrame(t, &buf, true, ContinuationMessage, make([]byte, 6), maskKey)
+
+	conn := newTestConn(buf.Bytes(), &Config{MaxMessageSize: 10})
+	_, _, err := conn.ReadMessage()
+	if !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("got error %v, want ErrMessageTooLarge", err)
+	}
+}
+
+func TestConnReadMessageRejectsUnmaskedFrameOnServer(t *testing.T) {
+	var buf bytes.Buffer
+	writeTestFrame(t, &buf, true, TextMessage, []byte("not masked"), nil)
+
+	conn := newTestConn(buf.Bytes(), nil)
+	_, _, err := conn.ReadMessage()
+	if !errors.Is(err, ErrFrameMaskMismatch) {
+		t.Fatalf("got error %v, want ErrFrameMaskMismatch", err)
+	}
+}
+
+func TestConnReadMessageRejectsMaskedFrameOnClient(t *testing.T) {
+	maskKey := []byte{0x01, 0x02, 0x03, 0x04}
+	var buf bytes.Buffer
+	writeTestFrame(t, &buf, true, TextMessage, []byte("should not be masked"), maskKey)
+
+	rw := bufio.NewReadWriter(bufio.NewReader(bytes.NewReader(buf.Bytes())), bufio.NewWriter(io.Discard))
+	conn := newConn(nil, rw, &Config{}, true)
+
+	_, _, err := conn.ReadMessage()
+	if !errors.Is(err, ErrFrameMaskMismatch) {
+		t.Fatalf("got error %v, want ErrFrameMaskMismatch", err)
+	}
+}
+
+func TestConnNextWriterExcludesInterleavedWriteMessage(t *testing.T) {
+	var out bytes.Buffer
+	rw := bufio.NewReadWriter(bufio.NewReader(bytes.NewReader(nil)), bufio.NewWriter(&out))
+	conn := newConn(nil, rw, &Config{}, false)
+
+	w, err := conn.NextWriter(BinaryMessage)
+	if err != nil {
+		t.Fatalf("NextWriter: %v", err)
+	}
+
+	// NextWriter holds conn's write lock until w is Closed, so however the
+	// scheduler interleaves these goroutines, WriteMessage's frame cannot
+	// land on the wire until after w.Close() below.
+	writeDone := make(chan struct{})
+	go func() {
+		defer close(writeDone)
+		if err := conn.WriteMessage(TextMessage, []byte("INTERLOPER")); err != nil {
+			t.Errorf("WriteMessage: %v", err)
+		}
+	}()
+
+	if _, err := w.Write(make([]byte, defaultWriteBufferSize+1)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	<-writeDone
+
+	r := bufio.NewReader(&out)
+
+	h1, err := parseFrameHeader(r)
+	if err != nil {
+		t.Fatalf("parse frame 1: %v", err)
+	}
+	if h1.final || h1.opcode != BinaryMessage {
+		t.Fatalf("frame 1 = %+v, want non-final Binary", h1)
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(h1.length)); err != nil {
+		t.Fatalf("skip frame 1 payload: %v", err)
+	}
+
+	h2, err := parseFrameHeader(r)
+	if err != nil {
+		t.Fatalf("parse frame 2: %v", err)
+	}
+	if !h2.final || h2.opcode != ContinuationMessage {
+		t.Fatalf("frame 2 = %+v, want final Continuation (no message should be spliced in here)", h2)
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(h2.length)); err != nil {
+		t.Fatalf("skip frame 2 payload: %v", err)
+	}
+
+	h3, err := parseFrameHeader(r)
+	if err != nil {
+		t.Fatalf("parse frame 3: %v", err)
+	}
+	if !h3.final || h3.opcode != TextMessage {
+		t.Fatalf("frame 3 = %+v, want final Text", h3)
+	}
+}
+
+func TestConnReadMessageAutoPongsPing(t *testing.T) {
+	maskKey := []byte{0xaa, 0xbb, 0xcc, 0xdd}
+	var in bytes.Buffer
+	writeTestFrame(t, &in, true, PingMessage, []byte("ping payload"), maskKey)
+	writeTestFrame(t, &in, true, TextMessage, []byte("after ping"), maskKey)
+
+	var out bytes.Buffer
+	rw := bufio.NewReadWriter(bufio.NewReader(&in), bufio.NewWriter(&out))
+	conn := newConn(nil, rw, &Config{}, false)
+
+	op, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if op != TextMessage || string(msg) != "after ping" {
+		t.Fatalf("got (%v, %q), want (TextMessage, %q)", op, msg, "after ping")
+	}
+
+	h, err := parseFrameHeader(bufio.NewReader(&out))
+	if err != nil {
+		t.Fatalf("parse auto-reply frame: %v", err)
+	}
+	if h.opcode != PongMessage {
+		t.Fatalf("got opcode %v, want PongMessage", h.opcode)
+	}
+}
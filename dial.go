@@ -0,0 +1,147 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrChallengeResponse is returned by Dial when the server's
+// Sec-WebSocket-Accept header doesn't match the value computed from the
+// Sec-WebSocket-Key the client sent (RFC 6455 §4.1 step 18).
+var ErrChallengeResponse = errors.New("websocket: challenge response mismatch")
+
+// DialConfig carries the client-side handshake options for Dial, analogous
+// to Config on the server side.
+type DialConfig struct {
+	// Origin, if non-empty, is sent as the Origin header.
+	Origin string
+
+	// Subprotocols lists, in order of preference, the application
+	// subprotocols offered to the server.
+	Subprotocols []string
+
+	// TLSConfig is used for wss:// connections. A nil value uses the
+	// default *tls.Config.
+	TLSConfig *tls.Config
+}
+
+// DialError reports that Dial failed, distinguishing the failing Config
+// from the underlying handshake or transport error.
+type DialError struct {
+	Config *DialConfig
+	Err    error
+}
+
+func (e *DialError) Error() string {
+	return fmt.Sprintf("websocket: dial: %v", e.Err)
+}
+
+func (e *DialError) Unwrap() error {
+	return e.Err
+}
+
+// Dial performs the client-side WebSocket handshake against urlStr, which
+// must have scheme ws or wss, and returns the resulting Conn. Frames
+// written by the returned Conn are masked per RFC 6455 §5.3, with a fresh
+// masking key generated for every frame.
+func Dial(urlStr string, config *DialConfig) (*Conn, error) {
+	if config == nil {
+		config = &DialConfig{}
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, &DialError{config, fmt.Errorf("invalid URL: %w", err)}
+	}
+
+	netConn, err := dialNetConn(u, config)
+	if err != nil {
+		return nil, &DialError{config, err}
+	}
+
+	key, err := generateChallengeKey()
+	if err != nil {
+		netConn.Close()
+		return nil, &DialError{config, fmt.Errorf("generate Sec-WebSocket-Key failed: %w", err)}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
+	if err != nil {
+		netConn.Close()
+		return nil, &DialError{config, fmt.Errorf("build handshake request failed: %w", err)}
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if config.Origin != "" {
+		req.Header.Set("Origin", config.Origin)
+	}
+	if len(config.Subprotocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(config.Subprotocols, ", "))
+	}
+
+	if err := req.Write(netConn); err != nil {
+		netConn.Close()
+		return nil, &DialError{config, fmt.Errorf("write handshake request failed: %w", err)}
+	}
+
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		netConn.Close()
+		return nil, &DialError{config, fmt.Errorf("read handshake response failed: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		netConn.Close()
+		return nil, &DialError{config, fmt.Errorf("unexpected handshake status: %s", resp.Status)}
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		netConn.Close()
+		return nil, &DialError{config, ErrChallengeResponse}
+	}
+
+	dialConfig := &Config{Origin: config.Origin, Protocol: resp.Header.Get("Sec-WebSocket-Protocol")}
+	buf := bufio.NewReadWriter(br, bufio.NewWriter(netConn))
+	return newConn(netConn, buf, dialConfig, true), nil
+}
+
+// dialNetConn opens the underlying network connection for u's scheme,
+// using TLS for wss.
+func dialNetConn(u *url.URL, config *DialConfig) (net.Conn, error) {
+	hostPort := u.Host
+	switch u.Scheme {
+	case "ws":
+		if !strings.Contains(hostPort, ":") {
+			hostPort += ":80"
+		}
+		return net.Dial("tcp", hostPort)
+	case "wss":
+		if !strings.Contains(hostPort, ":") {
+			hostPort += ":443"
+		}
+		return tls.Dial("tcp", hostPort, config.TLSConfig)
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+}
+
+// generateChallengeKey returns a base64-encoded 16 random bytes, suitable
+// for use as Sec-WebSocket-Key (RFC 6455 §4.1 step 7).
+func generateChallengeKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
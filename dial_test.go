@@ -0,0 +1,103 @@
+package websocket
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDialRoundTrip(t *testing.T) {
+	upgrader := Upgrader{Subprotocols: []string{"echo"}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r)
+		if err != nil {
+			t.Errorf("server Upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		op, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Errorf("server ReadMessage: %v", err)
+			return
+		}
+		if err := conn.WriteMessage(op, msg); err != nil {
+			t.Errorf("server WriteMessage: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	conn, err := Dial(url, &DialConfig{Subprotocols: []string{"echo"}})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+
+	if conn.Config.Protocol != "echo" {
+		t.Fatalf("got negotiated protocol %q, want %q", conn.Config.Protocol, "echo")
+	}
+
+	if err := conn.WriteMessage(TextMessage, []byte("ping")); err != nil {
+		t.Fatalf("client WriteMessage: %v", err)
+	}
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("client ReadMessage: %v", err)
+	}
+	if got := string(msg); got != "ping" {
+		t.Fatalf("got echoed message %q, want %q", got, "ping")
+	}
+}
+
+// dialAcceptMismatchServer is a minimal raw handshake responder that always
+// returns a Sec-WebSocket-Accept value that doesn't match the client's
+// Sec-WebSocket-Key, so Dial's RFC 6455 §4.1 step 18 verification can be
+// exercised without a full Upgrader on the other end.
+func dialAcceptMismatchServer(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		br := bufio.NewReader(conn)
+		if _, err := http.ReadRequest(br); err != nil {
+			return
+		}
+
+		fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\n"+
+			"Upgrade: websocket\r\n"+
+			"Connection: Upgrade\r\n"+
+			"Sec-WebSocket-Accept: not-the-right-value\r\n\r\n")
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return ln.Addr().String()
+}
+
+func TestDialRejectsChallengeResponseMismatch(t *testing.T) {
+	addr := dialAcceptMismatchServer(t)
+
+	_, err := Dial("ws://"+addr+"/", nil)
+	if err == nil {
+		t.Fatal("expected Dial to fail on a mismatched Sec-WebSocket-Accept")
+	}
+	if !errors.Is(err, ErrChallengeResponse) {
+		t.Fatalf("got error %v, want ErrChallengeResponse", err)
+	}
+
+	var dialErr *DialError
+	if !errors.As(err, &dialErr) {
+		t.Fatalf("got error of type %T, want *DialError", err)
+	}
+}
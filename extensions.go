@@ -0,0 +1,68 @@
+package websocket
+
+import (
+	"net/http"
+	"strings"
+)
+
+// deflateParams holds one offer (or the accepted negotiation) of the
+// permessage-deflate extension (RFC 7692 §7.1).
+//
+// client_max_window_bits and server_max_window_bits are deliberately not
+// supported: compress/flate always uses its fixed ~32 KB window and has no
+// way to honor a smaller one, so accepting a client's requested value here
+// without applying it would silently send a peer compressed data outside
+// the window it asked us to stay within. We neither request nor grant a
+// restricted window, which RFC 7692 §7.1 permits by simply omitting the
+// parameter from our response.
+type deflateParams struct {
+	clientNoContextTakeover bool
+	serverNoContextTakeover bool
+}
+
+// negotiatePerMessageDeflate scans the request's Sec-WebSocket-Extensions
+// header for a permessage-deflate offer and, if compression is enabled,
+// returns the parameters we accept. It reports false if compression isn't
+// enabled or the client didn't offer the extension.
+func negotiatePerMessageDeflate(enabled bool, r *http.Request) (deflateParams, bool) {
+	if !enabled {
+		return deflateParams{}, false
+	}
+
+	for _, line := range r.Header.Values("Sec-WebSocket-Extensions") {
+		for _, offer := range strings.Split(line, ",") {
+			fields := strings.Split(offer, ";")
+			if strings.TrimSpace(fields[0]) != "permessage-deflate" {
+				continue
+			}
+
+			var params deflateParams
+			for _, f := range fields[1:] {
+				name, _, _ := strings.Cut(strings.TrimSpace(f), "=")
+				switch name {
+				case "client_no_context_takeover":
+					params.clientNoContextTakeover = true
+				case "server_no_context_takeover":
+					params.serverNoContextTakeover = true
+				}
+			}
+			return params, true
+		}
+	}
+
+	return deflateParams{}, false
+}
+
+// responseValue renders the accepted parameters for the response's
+// Sec-WebSocket-Extensions header.
+func (p deflateParams) responseValue() string {
+	var b strings.Builder
+	b.WriteString("permessage-deflate")
+	if p.serverNoContextTakeover {
+		b.WriteString("; server_no_context_takeover")
+	}
+	if p.clientNoContextTakeover {
+		b.WriteString("; client_no_context_takeover")
+	}
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package websocket
+
+import (
+	"net/http"
+	"testing"
+)
+
+func newExtensionsRequest(t *testing.T, value string) *http.Request {
+	t.Helper()
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if value != "" {
+		r.Header.Set("Sec-WebSocket-Extensions", value)
+	}
+	return r
+}
+
+func TestNegotiatePerMessageDeflateDisabled(t *testing.T) {
+	r := newExtensionsRequest(t, "permessage-deflate")
+	_, ok := negotiatePerMessageDeflate(false, r)
+	if ok {
+		t.Fatal("expected no negotiation when compression is disabled")
+	}
+}
+
+func TestNegotiatePerMessageDeflateNotOffered(t *testing.T) {
+	r := newExtensionsRequest(t, "")
+	_, ok := negotiatePerMessageDeflate(true, r)
+	if ok {
+		t.Fatal("expected no negotiation when client didn't offer the extension")
+	}
+}
+
+func TestNegotiatePerMessageDeflateContextTakeover(t *testing.T) {
+	r := newExtensionsRequest(t, "permessage-deflate; client_no_context_takeover; server_no_context_takeover")
+	params, ok := negotiatePerMessageDeflate(true, r)
+	if !ok {
+		t.Fatal("expected negotiation to succeed")
+	}
+	if !params.clientNoContextTakeover || !params.serverNoContextTakeover {
+		t.Fatalf("got %+v, want both context-takeover flags set", params)
+	}
+}
+
+// Window-bits parameters aren't supported since compress/flate can't honor
+// anything but its fixed window, so they must not affect negotiation or
+// appear in the response.
+func TestNegotiatePerMessageDeflateIgnoresWindowBits(t *testing.T) {
+	r := newExtensionsRequest(t, "permessage-deflate; client_max_window_bits=10; server_max_window_bits=9")
+	params, ok := negotiatePerMessageDeflate(true, r)
+	if !ok {
+		t.Fatal("expected negotiation to succeed")
+	}
+	if got := params.responseValue(); got != "permessage-deflate" {
+		t.Fatalf("got response value %q, want bare %q", got, "permessage-deflate")
+	}
+}
+
+func TestDeflateParamsResponseValue(t *testing.T) {
+	params := deflateParams{clientNoContextTakeover: true}
+	if got, want := params.responseValue(), "permessage-deflate; client_no_context_takeover"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Opcode identifies the type of a WebSocket frame (RFC 6455 §5.2).
+type Opcode uint8
+
+const (
+	ContinuationMessage Opcode = 0x00
+	TextMessage         Opcode = 0x01
+	BinaryMessage       Opcode = 0x02
+	CloseMessage        Opcode = 0x08
+	PingMessage         Opcode = 0x09
+	PongMessage         Opcode = 0x0A
+)
+
+// isControl reports whether op identifies a control frame (RFC 6455 §5.5).
+func (op Opcode) isControl() bool {
+	return op&0x08 != 0
+}
+
+// frameHeader is a single parsed WebSocket frame header (RFC 6455 §5.2).
+// The payload is read separately by the caller, since for data frames it
+// may be arbitrarily large and is streamed rather than buffered whole.
+type frameHeader struct {
+	final      bool
+	rsv1       bool // set on compressed data frames per RFC 7692 §7.2.3
+	opcode     Opcode
+	length     uint64
+	maskingKey []byte
+}
+
+// parseFrameHeader reads and decodes one frame header from buf using
+// io.ReadFull, so a short read (e.g. a header split across TCP segments)
+// is never mistaken for a complete one. It does not read the payload.
+func parseFrameHeader(buf *bufio.Reader) (frameHeader, error) {
+	b, err := buf.ReadByte()
+	if err != nil {
+		return frameHeader{}, fmt.Errorf("read failed: %w", err)
+	}
+	fin := b & 0x80
+	rsv1 := b & 0x40
+	op := Opcode(b & 0x0F)
+
+	b, err = buf.ReadByte()
+	if err != nil {
+		return frameHeader{}, fmt.Errorf("read failed: %w", err)
+	}
+	mask := b & 0x80
+	length := uint64(b & 0x7F)
+
+	if length == 126 {
+		var b [2]byte
+		if _, err := io.ReadFull(buf, b[:]); err != nil {
+			return frameHeader{}, fmt.Errorf("read failed: %w", err)
+		}
+		length = uint64(binary.BigEndian.Uint16(b[:]))
+	} else if length == 127 {
+		var b [8]byte
+		if _, err := io.ReadFull(buf, b[:]); err != nil {
+			return frameHeader{}, fmt.Errorf("read failed: %w", err)
+		}
+		length = binary.BigEndian.Uint64(b[:])
+		if length>>63 != 0 {
+			return frameHeader{}, fmt.Errorf("read failed: invalid length with high bit set")
+		}
+	}
+
+	var maskingKey []byte
+	if mask == 0x80 {
+		maskingKey = make([]byte, 4)
+		if _, err := io.ReadFull(buf, maskingKey); err != nil {
+			return frameHeader{}, fmt.Errorf("read failed: %w", err)
+		}
+	}
+
+	return frameHeader{
+		final:      fin == 0x80,
+		rsv1:       rsv1 == 0x40,
+		opcode:     op,
+		length:     length,
+		maskingKey: maskingKey,
+	}, nil
+}
+
+// maskBytes applies the RFC 6455 §5.3 masking algorithm to b in place.
+func maskBytes(key []byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i%4]
+	}
+}
+
+// writeFrameHeader encodes a frame header of the given final/opcode/length
+// onto w. rsv1 should be set only on the first frame of a compressed
+// message (RFC 7692 §7.2.3). maskKey is nil for an unmasked server-to-client
+// frame, or a 4-byte masking key for a masked client-to-server frame (RFC
+// 6455 §5.3); the caller is responsible for XOR-masking the payload itself.
+func writeFrameHeader(w *bufio.Writer, final, rsv1 bool, op Opcode, length int, maskKey []byte) error {
+	var b0 byte
+	if final {
+		b0 |= 0x80
+	}
+	if rsv1 {
+		b0 |= 0x40
+	}
+	b0 |= byte(op)
+	if err := w.WriteByte(b0); err != nil {
+		return err
+	}
+
+	var b1 byte
+	if maskKey != nil {
+		b1 = 0x80
+	}
+
+	switch {
+	case length <= 125:
+		if err := w.WriteByte(b1 | byte(length)); err != nil {
+			return err
+		}
+	case length <= 0xFFFF:
+		if err := w.WriteByte(b1 | 126); err != nil {
+			return err
+		}
+		var b [2]byte
+		binary.BigEndian.PutUint16(b[:], uint16(length))
+		if _, err := w.Write(b[:]); err != nil {
+			return err
+		}
+	default:
+		if err := w.WriteByte(b1 | 127); err != nil {
+			return err
+		}
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(length))
+		if _, err := w.Write(b[:]); err != nil {
+			return err
+		}
+	}
+
+	if maskKey != nil {
+		if _, err := w.Write(maskKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
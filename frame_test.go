@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestParseFrameHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		final  bool
+		rsv1   bool
+		opcode Opcode
+		length int
+		mask   []byte
+	}{
+		{"empty unmasked", true, false, BinaryMessage, 0, nil},
+		{"small unmasked", true, false, TextMessage, 125, nil},
+		{"16-bit length", true, false, BinaryMessage, 126, nil},
+		{"16-bit boundary", true, false, BinaryMessage, 65535, nil},
+		{"64-bit length", false, false, BinaryMessage, 65536, nil},
+		{"masked small", true, false, TextMessage, 10, []byte{0x11, 0x22, 0x33, 0x44}},
+		{"masked 16-bit length", true, false, BinaryMessage, 200, []byte{0xde, 0xad, 0xbe, 0xef}},
+		{"compressed flag", true, true, BinaryMessage, 4, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			if err := writeFrameHeader(w, tt.final, tt.rsv1, tt.opcode, tt.length, tt.mask); err != nil {
+				t.Fatalf("writeFrameHeader: %v", err)
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("flush: %v", err)
+			}
+
+			h, err := parseFrameHeader(bufio.NewReader(&buf))
+			if err != nil {
+				t.Fatalf("parseFrameHeader: %v", err)
+			}
+			if h.final != tt.final || h.rsv1 != tt.rsv1 || h.opcode != tt.opcode || h.length != uint64(tt.length) {
+				t.Fatalf("got %+v, want final=%v rsv1=%v opcode=%v length=%d", h, tt.final, tt.rsv1, tt.opcode, tt.length)
+			}
+			if tt.mask == nil {
+				if h.maskingKey != nil {
+					t.Fatalf("got masking key %v, want none", h.maskingKey)
+				}
+			} else if !bytes.Equal(h.maskingKey, tt.mask) {
+				t.Fatalf("got masking key %v, want %v", h.maskingKey, tt.mask)
+			}
+		})
+	}
+}
+
+func TestParseFrameHeaderShortExtendedLength(t *testing.T) {
+	// Declares a 16-bit extended length but supplies only one of the two
+	// length bytes, so io.ReadFull must report a short read rather than
+	// silently treating it as a complete header.
+	raw := []byte{0x82, 126, 0x01}
+	if _, err := parseFrameHeader(bufio.NewReader(bytes.NewReader(raw))); err == nil {
+		t.Fatal("expected error for truncated extended length, got nil")
+	}
+}
+
+func TestParseFrameHeaderShortMaskingKey(t *testing.T) {
+	// Mask bit set, but fewer than 4 masking-key bytes follow.
+	raw := []byte{0x81, 0x85, 0x01, 0x02}
+	if _, err := parseFrameHeader(bufio.NewReader(bytes.NewReader(raw))); err == nil {
+		t.Fatal("expected error for truncated masking key, got nil")
+	}
+}
+
+func TestMaskBytes(t *testing.T) {
+	key := []byte{0x01, 0x02, 0x03, 0x04}
+	original := []byte("hello, websocket")
+
+	masked := append([]byte(nil), original...)
+	maskBytes(key, masked)
+	if bytes.Equal(masked, original) {
+		t.Fatal("maskBytes did not modify payload")
+	}
+
+	maskBytes(key, masked) // XOR is its own inverse
+	if !bytes.Equal(masked, original) {
+		t.Fatalf("got %q after double mask, want %q", masked, original)
+	}
+}
@@ -0,0 +1,60 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// acceptMagicGUID is appended to the client's Sec-WebSocket-Key before
+// hashing to produce Sec-WebSocket-Accept (RFC 6455 §1.3).
+const acceptMagicGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// acceptKey computes the Sec-WebSocket-Accept value for the given
+// Sec-WebSocket-Key, as sent by the server during the handshake and
+// verified by the client.
+func acceptKey(key string) string {
+	sum := sha1.New()
+	io.WriteString(sum, key+acceptMagicGUID)
+	return base64.StdEncoding.EncodeToString(sum.Sum(nil))
+}
+
+// selectSubprotocol picks the first entry in the client's
+// Sec-WebSocket-Protocol list that also appears in supported. It reports
+// false if supported is empty or none of the client's offers match.
+func selectSubprotocol(supported []string, r *http.Request) (string, bool) {
+	if len(supported) == 0 {
+		return "", false
+	}
+
+	for _, line := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, offered := range strings.Split(line, ",") {
+			offered = strings.TrimSpace(offered)
+			for _, s := range supported {
+				if offered == s {
+					return offered, true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+// checkSameOrigin is the default Upgrader.CheckOrigin: it allows requests
+// with no Origin header (non-browser clients) and otherwise requires the
+// Origin's host to match the request's Host.
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
@@ -0,0 +1,24 @@
+package websocket
+
+import "net/http"
+
+// Handler handles a single upgraded WebSocket connection. A Handler value
+// is itself an http.Handler: registering it directly on a mux performs the
+// handshake and hijack (via the zero-value Upgrader) before invoking the
+// function with the resulting Conn, so callers never reimplement the
+// upgrade dance.
+type Handler func(*Conn)
+
+// ServeHTTP implements http.Handler by upgrading the connection with a
+// default Upgrader and invoking h with the resulting Conn. Callers that
+// need to configure the handshake (e.g. compression or subprotocols)
+// should call Upgrader.Upgrade directly instead.
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := (Upgrader{}).Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	h(conn)
+}
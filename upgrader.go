@@ -0,0 +1,93 @@
+package websocket
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// Upgrader performs the WebSocket handshake (RFC 6455 §4.2.2) and produces
+// a Conn, in the style of gorilla/websocket's and x/net/websocket's
+// Upgrader/Config types.
+type Upgrader struct {
+	// EnableCompression opts into negotiating the permessage-deflate
+	// extension (RFC 7692) when the client offers it.
+	EnableCompression bool
+
+	// CompressionLevel is passed to compress/flate.NewWriter for outgoing
+	// messages. The zero value is flate.NoCompression.
+	CompressionLevel int
+
+	// Subprotocols lists the application subprotocols this handler
+	// supports. The first entry from the client's Sec-WebSocket-Protocol
+	// list that also appears here is selected, so the client's order
+	// decides and the order of this list does not; if none match, the
+	// handshake proceeds without a subprotocol.
+	Subprotocols []string
+
+	// CheckOrigin returns whether the handshake request's Origin header is
+	// acceptable. A nil CheckOrigin defaults to allowing only same-origin
+	// requests (comparing Origin's host to the request's Host).
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Upgrade validates the handshake request, hijacks the connection, and
+// returns the resulting Conn. On failure it writes an appropriate HTTP
+// error response to w and returns a non-nil error.
+func (u Upgrader) Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Header.Get("Upgrade") != "websocket" && r.Header.Get("Connection") != "Upgrade" {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return nil, fmt.Errorf("websocket: not a websocket handshake")
+	}
+
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = checkSameOrigin
+	}
+	if !checkOrigin(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return nil, fmt.Errorf("websocket: request Origin not allowed")
+	}
+
+	config := &Config{Origin: r.Header.Get("Origin")}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusNotImplemented), http.StatusNotImplemented)
+		return nil, fmt.Errorf("websocket: hijacking not supported")
+	}
+
+	accept := acceptKey(r.Header.Get("Sec-WebSocket-Key"))
+
+	w.Header().Set("Upgrade", "websocket")
+	w.Header().Set("Connection", "Upgrade")
+	w.Header().Set("Sec-WebSocket-Accept", accept)
+
+	if protocol, ok := selectSubprotocol(u.Subprotocols, r); ok {
+		w.Header().Set("Sec-WebSocket-Protocol", protocol)
+		config.Protocol = protocol
+	}
+
+	deflateParams, compressionAccepted := negotiatePerMessageDeflate(u.EnableCompression, r)
+	if compressionAccepted {
+		w.Header().Set("Sec-WebSocket-Extensions", deflateParams.responseValue())
+	}
+
+	w.WriteHeader(http.StatusSwitchingProtocols)
+
+	conn, buf, err := hj.Hijack()
+	if err != nil {
+		slog.Debug("hijack failed", slog.Any("error", err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, fmt.Errorf("hijack failed: %w", err)
+	}
+
+	c := newConn(conn, buf, config, false)
+	if compressionAccepted {
+		c.deflate = &deflateContext{
+			read:  newCompressionState(u.CompressionLevel, deflateParams.clientNoContextTakeover),
+			write: newCompressionState(u.CompressionLevel, deflateParams.serverNoContextTakeover),
+		}
+	}
+	return c, nil
+}
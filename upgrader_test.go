@@ -0,0 +1,115 @@
+package websocket
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// hijackableRecorder is an httptest.ResponseRecorder that also implements
+// http.Hijacker, backed by an in-memory net.Pipe, so Upgrader.Upgrade's
+// success path can be exercised without a real network listener.
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	serverConn net.Conn
+}
+
+func newHijackableRecorder() *hijackableRecorder {
+	serverConn, _ := net.Pipe()
+	return &hijackableRecorder{ResponseRecorder: httptest.NewRecorder(), serverConn: serverConn}
+}
+
+func (w *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rw := bufio.NewReadWriter(bufio.NewReader(w.serverConn), bufio.NewWriter(w.serverConn))
+	return w.serverConn, rw, nil
+}
+
+func newUpgradeRequest(t *testing.T, headers map[string]string) *http.Request {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	r.Header.Set("Sec-WebSocket-Version", "13")
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	return r
+}
+
+func TestUpgraderSelectsSubprotocol(t *testing.T) {
+	upgrader := Upgrader{Subprotocols: []string{"chat", "echo"}}
+	r := newUpgradeRequest(t, map[string]string{"Sec-WebSocket-Protocol": "echo, other"})
+	w := newHijackableRecorder()
+
+	conn, err := upgrader.Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade: %v", err)
+	}
+	defer conn.Close()
+
+	if conn.Config.Protocol != "echo" {
+		t.Fatalf("got negotiated protocol %q, want %q", conn.Config.Protocol, "echo")
+	}
+	if got := w.Header().Get("Sec-WebSocket-Protocol"); got != "echo" {
+		t.Fatalf("got response header %q, want %q", got, "echo")
+	}
+}
+
+func TestUpgraderRejectsDisallowedOrigin(t *testing.T) {
+	upgrader := Upgrader{CheckOrigin: func(r *http.Request) bool { return false }}
+	r := newUpgradeRequest(t, map[string]string{"Origin": "https://evil.example"})
+	w := httptest.NewRecorder()
+
+	_, err := upgrader.Upgrade(w, r)
+	if err == nil {
+		t.Fatal("expected Upgrade to reject the request")
+	}
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestUpgraderDefaultCheckOriginAllowsSameOrigin(t *testing.T) {
+	upgrader := Upgrader{}
+	r := newUpgradeRequest(t, map[string]string{"Origin": "http://example.com"})
+	r.Host = "example.com"
+	w := newHijackableRecorder()
+
+	conn, err := upgrader.Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade: %v", err)
+	}
+	conn.Close()
+}
+
+func TestUpgraderDefaultCheckOriginRejectsCrossOrigin(t *testing.T) {
+	upgrader := Upgrader{}
+	r := newUpgradeRequest(t, map[string]string{"Origin": "http://evil.example"})
+	r.Host = "example.com"
+	w := httptest.NewRecorder()
+
+	_, err := upgrader.Upgrade(w, r)
+	if err == nil {
+		t.Fatal("expected Upgrade to reject a cross-origin request")
+	}
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestUpgraderRejectsNonWebsocketRequest(t *testing.T) {
+	upgrader := Upgrader{}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	_, err := upgrader.Upgrade(w, r)
+	if err == nil {
+		t.Fatal("expected Upgrade to reject a non-websocket request")
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,217 @@
+package wsproxy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	websocket "github.com/thara/go-websocket"
+)
+
+// Options configures a Proxy.
+type Options struct {
+	// Writers maps a channel number to the writer that receives data the
+	// client sends on that channel. A channel with no writer is ignored.
+	// Each writer is closed when Run returns.
+	Writers map[int]io.WriteCloser
+
+	// Readers maps a channel number to a reader whose output is sent to
+	// the client on that channel. Each reader is pumped by its own
+	// goroutine until it returns an error, and is closed when Run returns
+	// so a goroutine blocked in Read is unblocked instead of leaked.
+	Readers map[int]io.ReadCloser
+
+	// PingInterval is how often a keepalive Ping is sent to the client.
+	// Zero uses a 30 second default.
+	PingInterval time.Duration
+}
+
+// Proxy bridges conn, which must already be upgraded with the
+// ChannelProtocol or Base64ChannelProtocol subprotocol, to opts' per-channel
+// readers and writers.
+type Proxy struct {
+	conn   *websocket.Conn
+	base64 bool
+
+	writers map[int]io.WriteCloser
+	readers map[int]io.ReadCloser
+
+	pingInterval time.Duration
+}
+
+// New creates a Proxy for conn using opts. It reports an error if conn did
+// not negotiate a subprotocol this package understands.
+func New(conn *websocket.Conn, opts Options) (*Proxy, error) {
+	var base64Encoded bool
+	switch conn.Config.Protocol {
+	case Base64ChannelProtocol:
+		base64Encoded = true
+	case ChannelProtocol:
+		base64Encoded = false
+	default:
+		return nil, fmt.Errorf("wsproxy: unsupported subprotocol %q", conn.Config.Protocol)
+	}
+
+	pingInterval := opts.PingInterval
+	if pingInterval <= 0 {
+		pingInterval = defaultPingInterval
+	}
+
+	return &Proxy{
+		conn:         conn,
+		base64:       base64Encoded,
+		writers:      opts.Writers,
+		readers:      opts.Readers,
+		pingInterval: pingInterval,
+	}, nil
+}
+
+// Run pumps data between conn and the configured readers/writers until the
+// client closes the connection, or an unrecoverable frame error occurs. It
+// blocks until every reader goroutine has exited and conn, along with every
+// configured reader and writer, has been closed, so both sides are always
+// shut down together and no pump goroutine is left blocked in Read.
+func (p *Proxy) Run() error {
+	done := make(chan struct{})
+	var closeOnce sync.Once
+	stop := func() {
+		closeOnce.Do(func() {
+			close(done)
+			p.conn.Close()
+			for _, r := range p.readers {
+				r.Close()
+			}
+			for _, w := range p.writers {
+				w.Close()
+			}
+		})
+	}
+	defer stop()
+
+	var wg sync.WaitGroup
+	for ch, r := range p.readers {
+		wg.Add(1)
+		go func(ch int, r io.Reader) {
+			defer wg.Done()
+			p.pumpReader(ch, r)
+		}(ch, r)
+	}
+
+	pingDone := make(chan struct{})
+	go func() {
+		defer close(pingDone)
+		p.pingLoop(done)
+	}()
+
+	err := p.readLoop()
+	stop()
+	wg.Wait()
+	<-pingDone
+	return err
+}
+
+// pingLoop sends a keepalive Ping every PingInterval until done is closed
+// or a write fails.
+func (p *Proxy) pingLoop(done <-chan struct{}) {
+	ticker := time.NewTicker(p.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := p.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readLoop demuxes incoming client frames to their target writer until the
+// connection closes or an unrecoverable error occurs.
+func (p *Proxy) readLoop() error {
+	for {
+		op, payload, err := p.conn.ReadMessage()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("wsproxy: read failed: %w", err)
+		}
+		if op != websocket.BinaryMessage && op != websocket.TextMessage {
+			continue
+		}
+		if err := p.demux(payload); err != nil {
+			return err
+		}
+	}
+}
+
+// demux decodes frame's leading channel byte and writes the remainder to
+// that channel's configured Writer, if any.
+func (p *Proxy) demux(frame []byte) error {
+	if len(frame) == 0 {
+		return nil
+	}
+
+	var ch int
+	var data []byte
+	if p.base64 {
+		ch = int(frame[0] - '0')
+		decoded, err := base64.StdEncoding.DecodeString(string(frame[1:]))
+		if err != nil {
+			return fmt.Errorf("wsproxy: invalid base64 payload on channel %d: %w", ch, err)
+		}
+		data = decoded
+	} else {
+		ch = int(frame[0])
+		data = frame[1:]
+	}
+
+	w, ok := p.writers[ch]
+	if !ok {
+		return nil
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// pumpReader copies r's output to the client on channel ch until r returns
+// an error or a write to the client fails. r is closed by Run's stop, which
+// unblocks a Read that's still in flight when the proxy shuts down.
+func (p *Proxy) pumpReader(ch int, r io.Reader) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if writeErr := p.writeChannel(ch, buf[:n]); writeErr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// writeChannel sends data to the client prefixed with ch's channel byte,
+// framed and encoded as required by the negotiated subprotocol.
+// Conn.WriteMessage is safe to call concurrently, which pumpReader
+// goroutines for different channels and the keepalive ping all do.
+func (p *Proxy) writeChannel(ch int, data []byte) error {
+	if p.base64 {
+		var buf bytes.Buffer
+		buf.WriteByte(byte('0' + ch))
+		buf.WriteString(base64.StdEncoding.EncodeToString(data))
+		return p.conn.WriteMessage(websocket.TextMessage, buf.Bytes())
+	}
+
+	frame := make([]byte, len(data)+1)
+	frame[0] = byte(ch)
+	copy(frame[1:], data)
+	return p.conn.WriteMessage(websocket.BinaryMessage, frame)
+}
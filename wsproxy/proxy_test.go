@@ -0,0 +1,84 @@
+package wsproxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	websocket "github.com/thara/go-websocket"
+)
+
+// blockingReader never returns from Read until Close is called, simulating
+// a backend stream (e.g. a container's stdout) that has nothing to say.
+// It records whether Close unblocked it, which is exactly the goroutine
+// leak Run's shutdown must prevent.
+type blockingReader struct {
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newBlockingReader() *blockingReader {
+	return &blockingReader{closed: make(chan struct{})}
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	<-r.closed
+	return 0, io.EOF
+}
+
+func (r *blockingReader) Close() error {
+	r.once.Do(func() { close(r.closed) })
+	return nil
+}
+
+type discardWriteCloser struct{ io.Writer }
+
+func (discardWriteCloser) Close() error { return nil }
+
+func TestProxyRunStopsOnClientDisconnect(t *testing.T) {
+	reader := newBlockingReader()
+
+	runErr := make(chan error, 1)
+	upgrader := websocket.Upgrader{Subprotocols: []string{ChannelProtocol}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r)
+		if err != nil {
+			t.Errorf("server Upgrade: %v", err)
+			return
+		}
+
+		p, err := New(conn, Options{
+			Readers: map[int]io.ReadCloser{StdoutChannel: reader},
+			Writers: map[int]io.WriteCloser{StdinChannel: discardWriteCloser{io.Discard}},
+		})
+		if err != nil {
+			t.Errorf("New: %v", err)
+			return
+		}
+		runErr <- p.Run()
+	}))
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	conn, err := websocket.Dial(url, &websocket.DialConfig{Subprotocols: []string{ChannelProtocol}})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	conn.Close()
+
+	select {
+	case <-runErr:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the client disconnected")
+	}
+
+	select {
+	case <-reader.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not close the backend reader, leaking its pump goroutine")
+	}
+}
@@ -0,0 +1,27 @@
+// Package wsproxy bridges a WebSocket connection using the channel.k8s.io
+// or base64.channel.k8s.io subprotocol (as used by Kubernetes exec/attach
+// endpoints) to a set of per-channel io.Writer/io.Reader pairs, demuxing
+// and muxing frames by their leading channel-number byte.
+package wsproxy
+
+import "time"
+
+// Subprotocol names understood by Proxy. Register one or both with
+// Upgrader.Subprotocols so the handshake negotiates them.
+const (
+	ChannelProtocol       = "channel.k8s.io"
+	Base64ChannelProtocol = "base64.channel.k8s.io"
+)
+
+// Standard channel numbers, matching the POSIX file descriptors used by
+// remote exec/attach.
+const (
+	StdinChannel  = 0
+	StdoutChannel = 1
+	StderrChannel = 2
+	ErrChannel    = 3
+	ResizeChannel = 4
+)
+
+// defaultPingInterval is used when Options.PingInterval is zero.
+const defaultPingInterval = 30 * time.Second